@@ -0,0 +1,51 @@
+//go:build linux || darwin
+
+package aferofuse
+
+import (
+	"os"
+	"os/user"
+	"strconv"
+
+	"bazil.org/fuse"
+)
+
+// fillAttr translates an os.FileInfo, as returned by any afero.Fs, into the
+// fuse.Attr the kernel expects back from Getattr/Attr.
+func fillAttr(fi os.FileInfo, a *fuse.Attr) {
+	a.Size = uint64(fi.Size())
+	a.Mode = fi.Mode()
+	a.Mtime = fi.ModTime()
+	a.Ctime = fi.ModTime()
+	a.Uid = currentUID
+	a.Gid = currentGID
+}
+
+var (
+	currentUID = lookupUID()
+	currentGID = lookupGID()
+)
+
+func lookupUID() uint32 {
+	u, err := user.Current()
+	if err != nil {
+		return 0
+	}
+	n, err := strconv.ParseUint(u.Uid, 10, 32)
+	if err != nil {
+		return 0
+	}
+	return uint32(n)
+}
+
+func lookupGID() uint32 {
+	u, err := user.Current()
+	if err != nil {
+		return 0
+	}
+	n, err := strconv.ParseUint(u.Gid, 10, 32)
+	if err != nil {
+		return 0
+	}
+	return uint32(n)
+}
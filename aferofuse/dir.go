@@ -0,0 +1,139 @@
+//go:build linux || darwin
+
+package aferofuse
+
+import (
+	"context"
+	"os"
+
+	"bazil.org/fuse"
+	"bazil.org/fuse/fs"
+)
+
+// dir implements a directory node, backed by the path path within fs.afero.
+type dir struct {
+	fs   *FS
+	path string
+}
+
+var (
+	_ fs.Node               = (*dir)(nil)
+	_ fs.NodeStringLookuper = (*dir)(nil)
+	_ fs.HandleReadDirAller = (*dir)(nil)
+	_ fs.NodeMkdirer        = (*dir)(nil)
+	_ fs.NodeRemover        = (*dir)(nil)
+	_ fs.NodeRenamer        = (*dir)(nil)
+	_ fs.NodeCreater        = (*dir)(nil)
+	_ fs.NodeSymlinker      = (*dir)(nil)
+)
+
+func (d *dir) Attr(ctx context.Context, a *fuse.Attr) error {
+	fi, err := d.fs.afero.Stat(d.path)
+	if err != nil {
+		return toFuseErr(err)
+	}
+	fillAttr(fi, a)
+	a.Valid = d.fs.cfg.attrValid
+	return nil
+}
+
+func (d *dir) Lookup(ctx context.Context, name string) (fs.Node, error) {
+	childPath := d.fs.child(d.path, name)
+	fi, err := d.fs.afero.Stat(childPath)
+	if err != nil {
+		return nil, fuse.ENOENT
+	}
+	if fi.IsDir() {
+		return &dir{fs: d.fs, path: childPath}, nil
+	}
+	return &file{fs: d.fs, path: childPath}, nil
+}
+
+func (d *dir) ReadDirAll(ctx context.Context) ([]fuse.Dirent, error) {
+	f, err := d.fs.afero.Open(d.path)
+	if err != nil {
+		return nil, toFuseErr(err)
+	}
+	defer f.Close()
+
+	infos, err := f.Readdir(-1)
+	if err != nil {
+		return nil, toFuseErr(err)
+	}
+
+	ents := make([]fuse.Dirent, 0, len(infos))
+	for _, fi := range infos {
+		typ := fuse.DT_File
+		if fi.IsDir() {
+			typ = fuse.DT_Dir
+		}
+		ents = append(ents, fuse.Dirent{Name: fi.Name(), Type: typ})
+	}
+	return ents, nil
+}
+
+func (d *dir) Mkdir(ctx context.Context, req *fuse.MkdirRequest) (fs.Node, error) {
+	if d.fs.cfg.readOnly {
+		return nil, fuse.EPERM
+	}
+	childPath := d.fs.child(d.path, req.Name)
+	if err := d.fs.afero.Mkdir(childPath, req.Mode); err != nil {
+		return nil, toFuseErr(err)
+	}
+	return &dir{fs: d.fs, path: childPath}, nil
+}
+
+func (d *dir) Create(ctx context.Context, req *fuse.CreateRequest, resp *fuse.CreateResponse) (fs.Node, fs.Handle, error) {
+	if d.fs.cfg.readOnly {
+		return nil, nil, fuse.EPERM
+	}
+	childPath := d.fs.child(d.path, req.Name)
+	f, err := d.fs.afero.OpenFile(childPath, os.O_CREATE|os.O_RDWR|os.O_TRUNC, req.Mode)
+	if err != nil {
+		return nil, nil, toFuseErr(err)
+	}
+	n := &file{fs: d.fs, path: childPath}
+	return n, &handle{file: f}, nil
+}
+
+func (d *dir) Remove(ctx context.Context, req *fuse.RemoveRequest) error {
+	if d.fs.cfg.readOnly {
+		return fuse.EPERM
+	}
+	childPath := d.fs.child(d.path, req.Name)
+	if err := d.fs.afero.Remove(childPath); err != nil {
+		return toFuseErr(err)
+	}
+	return nil
+}
+
+func (d *dir) Rename(ctx context.Context, req *fuse.RenameRequest, newDir fs.Node) error {
+	if d.fs.cfg.readOnly {
+		return fuse.EPERM
+	}
+	nd, ok := newDir.(*dir)
+	if !ok {
+		return fuse.EIO
+	}
+	oldPath := d.fs.child(d.path, req.OldName)
+	newPath := d.fs.child(nd.path, req.NewName)
+	if err := d.fs.afero.Rename(oldPath, newPath); err != nil {
+		return toFuseErr(err)
+	}
+	return nil
+}
+
+func (d *dir) Symlink(ctx context.Context, req *fuse.SymlinkRequest) (fs.Node, error) {
+	if d.fs.cfg.readOnly {
+		return nil, fuse.EPERM
+	}
+	linker, ok := d.fs.afero.(Symlinker)
+	if !ok {
+		return nil, fuse.Errno(fuse.ENOSYS)
+	}
+	newPath := d.fs.child(d.path, req.NewName)
+	if err := linker.Symlink(req.Target, newPath); err != nil {
+		return nil, toFuseErr(err)
+	}
+	return &file{fs: d.fs, path: newPath}, nil
+}
@@ -0,0 +1,15 @@
+//go:build linux || darwin
+
+// Package aferofuse serves an afero.Fs as a FUSE mount using bazil.org/fuse,
+// so that any afero backend (mem.MemMapFs, afero.BasePathFs, ...) can be
+// browsed and edited with ordinary filesystem tools through the kernel VFS.
+//
+//	srv, err := aferofuse.Mount(mem.NewMemMapFs(), "/mnt/afero")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	defer srv.Close()
+//
+// Only platforms bazil.org/fuse supports (linux, darwin) can mount; building
+// for other GOOS values fails at compile time rather than at Mount.
+package aferofuse
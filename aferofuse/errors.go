@@ -0,0 +1,28 @@
+//go:build linux || darwin
+
+package aferofuse
+
+import (
+	"errors"
+	"os"
+
+	"bazil.org/fuse"
+)
+
+// toFuseErr maps an afero/os error onto the fuse.Errno the kernel expects,
+// falling back to EIO for anything it doesn't recognize rather than letting
+// an arbitrary Go error value reach the kernel.
+func toFuseErr(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, os.ErrNotExist):
+		return fuse.ENOENT
+	case errors.Is(err, os.ErrExist):
+		return fuse.EEXIST
+	case errors.Is(err, os.ErrPermission):
+		return fuse.EPERM
+	default:
+		return fuse.EIO
+	}
+}
@@ -0,0 +1,113 @@
+//go:build linux || darwin
+
+package aferofuse
+
+import (
+	"context"
+	"os"
+
+	"bazil.org/fuse"
+	"bazil.org/fuse/fs"
+)
+
+// file implements a regular-file (or symlink) node, backed by the path path
+// within fs.afero.
+type file struct {
+	fs   *FS
+	path string
+}
+
+var (
+	_ fs.Node           = (*file)(nil)
+	_ fs.NodeOpener     = (*file)(nil)
+	_ fs.NodeSetattrer  = (*file)(nil)
+	_ fs.NodeFsyncer    = (*file)(nil)
+	_ fs.NodeReadlinker = (*file)(nil)
+)
+
+func (f *file) Attr(ctx context.Context, a *fuse.Attr) error {
+	fi, err := f.stat()
+	if err != nil {
+		return toFuseErr(err)
+	}
+	fillAttr(fi, a)
+	a.Valid = f.fs.cfg.attrValid
+	return nil
+}
+
+func (f *file) stat() (os.FileInfo, error) {
+	if linker, ok := f.fs.afero.(Symlinker); ok {
+		if fi, err := linker.Lstat(f.path); err == nil {
+			return fi, nil
+		}
+	}
+	return f.fs.afero.Stat(f.path)
+}
+
+func (f *file) Open(ctx context.Context, req *fuse.OpenRequest, resp *fuse.OpenResponse) (fs.Handle, error) {
+	flag := os.O_RDONLY
+	switch {
+	case req.Flags.IsReadWrite():
+		flag = os.O_RDWR
+	case req.Flags.IsWriteOnly():
+		flag = os.O_WRONLY
+	}
+	if f.fs.cfg.readOnly && flag != os.O_RDONLY {
+		return nil, fuse.EPERM
+	}
+	af, err := f.fs.afero.OpenFile(f.path, flag, 0)
+	if err != nil {
+		return nil, toFuseErr(err)
+	}
+	return &handle{file: af}, nil
+}
+
+func (f *file) Setattr(ctx context.Context, req *fuse.SetattrRequest, resp *fuse.SetattrResponse) error {
+	if f.fs.cfg.readOnly {
+		return fuse.EPERM
+	}
+	if req.Valid.Size() {
+		af, err := f.fs.afero.OpenFile(f.path, os.O_WRONLY, 0)
+		if err != nil {
+			return toFuseErr(err)
+		}
+		defer af.Close()
+		if err := af.Truncate(int64(req.Size)); err != nil {
+			return toFuseErr(err)
+		}
+	}
+	if req.Valid.Mode() {
+		if err := f.fs.afero.Chmod(f.path, req.Mode); err != nil {
+			return toFuseErr(err)
+		}
+	}
+	if req.Valid.Mtime() {
+		if err := f.fs.afero.Chtimes(f.path, req.Atime, req.Mtime); err != nil {
+			return toFuseErr(err)
+		}
+	}
+	fi, err := f.stat()
+	if err != nil {
+		return toFuseErr(err)
+	}
+	fillAttr(fi, &resp.Attr)
+	resp.Attr.Valid = f.fs.cfg.attrValid
+	return nil
+}
+
+func (f *file) Fsync(ctx context.Context, req *fuse.FsyncRequest) error {
+	af, err := f.fs.afero.OpenFile(f.path, os.O_WRONLY, 0)
+	if err != nil {
+		return toFuseErr(err)
+	}
+	defer af.Close()
+	return toFuseErr(af.Sync())
+}
+
+func (f *file) Readlink(ctx context.Context, req *fuse.ReadlinkRequest) (string, error) {
+	linker, ok := f.fs.afero.(Symlinker)
+	if !ok {
+		return "", fuse.Errno(fuse.ENOSYS)
+	}
+	return linker.Readlink(f.path)
+}
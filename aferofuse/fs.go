@@ -0,0 +1,30 @@
+//go:build linux || darwin
+
+package aferofuse
+
+import (
+	"path"
+
+	"bazil.org/fuse/fs"
+	"github.com/spf13/afero"
+)
+
+// FS implements bazil.org/fuse/fs.FS on top of an afero.Fs. All nodes carry
+// the full slash-separated path from the root of afero the same way afero
+// itself addresses files, so translating between a fuse inode and an afero
+// path is just string handling.
+type FS struct {
+	afero afero.Fs
+	cfg   config
+}
+
+var _ fs.FS = (*FS)(nil)
+
+// Root returns the root directory node of the mounted afero.Fs.
+func (f *FS) Root() (fs.Node, error) {
+	return &dir{fs: f, path: "/"}, nil
+}
+
+func (f *FS) child(dirPath, name string) string {
+	return path.Join(dirPath, name)
+}
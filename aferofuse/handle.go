@@ -0,0 +1,52 @@
+//go:build linux || darwin
+
+package aferofuse
+
+import (
+	"context"
+
+	"bazil.org/fuse"
+	"bazil.org/fuse/fs"
+	"github.com/spf13/afero"
+)
+
+// handle is an open file descriptor against an afero.File, implementing the
+// read/write/flush/release side of bazil.org/fuse's fs.Handle.
+type handle struct {
+	file afero.File
+}
+
+var (
+	_ fs.Handle         = (*handle)(nil)
+	_ fs.HandleReader   = (*handle)(nil)
+	_ fs.HandleWriter   = (*handle)(nil)
+	_ fs.HandleFlusher  = (*handle)(nil)
+	_ fs.HandleReleaser = (*handle)(nil)
+)
+
+func (h *handle) Read(ctx context.Context, req *fuse.ReadRequest, resp *fuse.ReadResponse) error {
+	buf := make([]byte, req.Size)
+	n, err := h.file.ReadAt(buf, req.Offset)
+	if err != nil && n == 0 {
+		return toFuseErr(err)
+	}
+	resp.Data = buf[:n]
+	return nil
+}
+
+func (h *handle) Write(ctx context.Context, req *fuse.WriteRequest, resp *fuse.WriteResponse) error {
+	n, err := h.file.WriteAt(req.Data, req.Offset)
+	if err != nil {
+		return toFuseErr(err)
+	}
+	resp.Size = n
+	return nil
+}
+
+func (h *handle) Flush(ctx context.Context, req *fuse.FlushRequest) error {
+	return toFuseErr(h.file.Sync())
+}
+
+func (h *handle) Release(ctx context.Context, req *fuse.ReleaseRequest) error {
+	return toFuseErr(h.file.Close())
+}
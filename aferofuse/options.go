@@ -0,0 +1,40 @@
+//go:build linux || darwin
+
+package aferofuse
+
+import "time"
+
+// config holds the tunables an Option can adjust. It is unexported: callers
+// only ever interact with it through Option functions passed to Mount.
+type config struct {
+	volumeName string
+	readOnly   bool
+	attrValid  time.Duration
+}
+
+func defaultConfig() config {
+	return config{
+		volumeName: "afero",
+		attrValid:  time.Second,
+	}
+}
+
+// Option configures a Mount call.
+type Option func(*config)
+
+// VolumeName sets the name reported to the OS for the mounted volume.
+func VolumeName(name string) Option {
+	return func(c *config) { c.volumeName = name }
+}
+
+// ReadOnly mounts the afero.Fs read-only, rejecting Write, Mkdir, Remove,
+// Rename and Setattr at the FUSE layer before they ever reach the Fs.
+func ReadOnly() Option {
+	return func(c *config) { c.readOnly = true }
+}
+
+// AttrValid sets how long the kernel may cache Attr responses for a node
+// before calling Getattr again.
+func AttrValid(d time.Duration) Option {
+	return func(c *config) { c.attrValid = d }
+}
@@ -0,0 +1,59 @@
+//go:build linux || darwin
+
+package aferofuse
+
+import (
+	"bazil.org/fuse"
+	bazilfs "bazil.org/fuse/fs"
+	"github.com/spf13/afero"
+)
+
+// Server is a running FUSE mount of an afero.Fs. Close it to unmount and
+// release the underlying kernel connection.
+type Server struct {
+	conn       *fuse.Conn
+	mountpoint string
+	serveErr   chan error
+}
+
+// Mount serves afs as a FUSE filesystem at mountpoint. fuse.Mount only
+// returns once the kernel has acknowledged the mount, so the call blocks at
+// most that long; use (*Server).Wait to block until the mount is unmounted
+// (e.g. by `umount`) and the serve loop returns.
+func Mount(afs afero.Fs, mountpoint string, opts ...Option) (*Server, error) {
+	cfg := defaultConfig()
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	conn, err := fuse.Mount(
+		mountpoint,
+		fuse.FSName(cfg.volumeName),
+		fuse.Subtype("aferofuse"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	srv := &Server{conn: conn, mountpoint: mountpoint, serveErr: make(chan error, 1)}
+	afuseFS := &FS{afero: afs, cfg: cfg}
+
+	go func() {
+		srv.serveErr <- bazilfs.Serve(conn, afuseFS)
+	}()
+
+	return srv, nil
+}
+
+// Wait blocks until the mount is unmounted and the serve loop returns.
+func (s *Server) Wait() error {
+	return <-s.serveErr
+}
+
+// Close unmounts the filesystem and closes the underlying kernel connection.
+func (s *Server) Close() error {
+	if err := fuse.Unmount(s.mountpoint); err != nil {
+		return err
+	}
+	return s.conn.Close()
+}
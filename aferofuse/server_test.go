@@ -0,0 +1,65 @@
+//go:build linux || darwin
+
+package aferofuse
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/AbsFS/afero/mem"
+)
+
+// TestMountMemMapFs mounts a MemMapFs, writes a file, reads it back and
+// truncates it through the kernel VFS, and unmounts. It does not run the
+// Seek/ReadAt/WriteAt steps of mem.TestFile's matrix. FUSE mounts require /dev/fuse and appropriate privileges, which
+// typical CI sandboxes don't grant, so the test skips itself rather than
+// failing when mounting isn't possible.
+func TestMountMemMapFs(t *testing.T) {
+	mountpoint, err := ioutil.TempDir("", "aferofuse-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(mountpoint)
+
+	fs := mem.NewMemMapFs()
+	srv, err := Mount(fs, mountpoint)
+	if err != nil {
+		t.Skipf("skipping: could not mount FUSE (likely no /dev/fuse in this sandbox): %v", err)
+	}
+	defer srv.Close()
+
+	path := filepath.Join(mountpoint, "hello.txt")
+	const want = "hello, world\n"
+
+	if err := ioutil.WriteFile(path, []byte(want), 0o644); err != nil {
+		t.Fatalf("write through mount: %v", err)
+	}
+
+	got, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read through mount: %v", err)
+	}
+	if string(got) != want {
+		t.Errorf("read back %q, want %q", got, want)
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatalf("reopen through mount: %v", err)
+	}
+	if err := f.Truncate(5); err != nil {
+		f.Close()
+		t.Fatalf("truncate through mount: %v", err)
+	}
+	f.Close()
+
+	got, err = ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read after truncate: %v", err)
+	}
+	if string(got) != want[:5] {
+		t.Errorf("after truncate got %q, want %q", got, want[:5])
+	}
+}
@@ -0,0 +1,15 @@
+//go:build linux || darwin
+
+package aferofuse
+
+import "os"
+
+// Symlinker is an optional extension afero.Fs implementations may provide to
+// support symbolic links. afero's core Fs interface has no notion of links,
+// so aferofuse type-asserts for it and simply reports ENOSYS for Symlink and
+// Readlink requests against filesystems that don't implement it.
+type Symlinker interface {
+	Symlink(oldname, newname string) error
+	Readlink(name string) (string, error)
+	Lstat(name string) (os.FileInfo, error)
+}
@@ -0,0 +1,13 @@
+// Package aferowebdav adapts an afero.Fs into a golang.org/x/net/webdav.FileSystem,
+// so that any afero backend (mem.MemMapFs, afero.BasePathFs, afero.CopyOnWriteFs, ...)
+// can be served directly over WebDAV.
+//
+// A minimal server looks like:
+//
+//	fs := mem.NewMemMapFs()
+//	handler := &webdav.Handler{
+//		FileSystem: aferowebdav.NewFileSystem(fs),
+//		LockSystem: aferowebdav.NewMemLS(),
+//	}
+//	http.ListenAndServe(":8080", handler)
+package aferowebdav
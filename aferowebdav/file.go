@@ -0,0 +1,21 @@
+package aferowebdav
+
+import (
+	"os"
+
+	"github.com/spf13/afero"
+)
+
+// file adapts an afero.File to webdav.File, which additionally requires
+// Readdir (already satisfied by afero.File) and Stat.
+type file struct {
+	afero.File
+}
+
+func (f *file) Readdir(count int) ([]os.FileInfo, error) {
+	return f.File.Readdir(count)
+}
+
+func (f *file) Stat() (os.FileInfo, error) {
+	return f.File.Stat()
+}
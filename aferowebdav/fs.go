@@ -0,0 +1,74 @@
+package aferowebdav
+
+import (
+	"context"
+	"os"
+
+	"github.com/spf13/afero"
+	"golang.org/x/net/webdav"
+)
+
+// FileSystem adapts an afero.Fs to the webdav.FileSystem interface expected
+// by webdav.Handler.
+type FileSystem struct {
+	fs afero.Fs
+}
+
+// NewFileSystem returns a webdav.FileSystem backed by fs. The root of fs is
+// served as the root of the WebDAV tree.
+func NewFileSystem(fs afero.Fs) *FileSystem {
+	return &FileSystem{fs: fs}
+}
+
+var _ webdav.FileSystem = (*FileSystem)(nil)
+
+func (fs *FileSystem) Mkdir(_ context.Context, name string, perm os.FileMode) error {
+	name, err := toAferoPath(name)
+	if err != nil {
+		return err
+	}
+	return fs.fs.Mkdir(name, perm)
+}
+
+func (fs *FileSystem) OpenFile(_ context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+	name, err := toAferoPath(name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := fs.fs.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return &file{File: f}, nil
+}
+
+func (fs *FileSystem) RemoveAll(_ context.Context, name string) error {
+	name, err := toAferoPath(name)
+	if err != nil {
+		return err
+	}
+	if name == "/" {
+		return errInvalidName
+	}
+	return fs.fs.RemoveAll(name)
+}
+
+func (fs *FileSystem) Rename(_ context.Context, oldName, newName string) error {
+	oldName, err := toAferoPath(oldName)
+	if err != nil {
+		return err
+	}
+	newName, err = toAferoPath(newName)
+	if err != nil {
+		return err
+	}
+	return fs.fs.Rename(oldName, newName)
+}
+
+func (fs *FileSystem) Stat(_ context.Context, name string) (os.FileInfo, error) {
+	name, err := toAferoPath(name)
+	if err != nil {
+		return nil, err
+	}
+	return fs.fs.Stat(name)
+}
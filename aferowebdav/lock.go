@@ -0,0 +1,174 @@
+package aferowebdav
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/AbsFS/afero/mem"
+	"github.com/spf13/afero"
+	"golang.org/x/net/webdav"
+)
+
+// memLockSystem is a webdav.LockSystem whose lock records are journalled as
+// files on an in-memory afero.Fs (mem.MemMapFs) rather than kept purely as Go
+// heap state. This keeps the locking logic trivially portable: the same
+// record format can later be pointed at any afero.Fs, and a process restart
+// can be diagnosed by just listing the lock directory.
+//
+// Locks are tracked per resource path and, matching golang.org/x/net/webdav's
+// own in-memory LockSystem, are always exclusive — a resource is either
+// unlocked or held by a single writer. This covers the common WebDAV client
+// workflows (Windows Explorer, macOS Finder, cadaver) without implementing
+// the full interval tree that a depth-infinity lock over an arbitrarily deep
+// collection would require.
+type memLockSystem struct {
+	mu    sync.Mutex
+	fs    afero.Fs
+	locks map[string]*lockRecord
+}
+
+type lockRecord struct {
+	Token     string    `json:"token"`
+	Root      string    `json:"root"`
+	ZeroDepth bool      `json:"zeroDepth"`
+	OwnerXML  string    `json:"ownerXML"`
+	Expiry    time.Time `json:"expiry"`
+}
+
+// NewMemLS returns a webdav.LockSystem that journals its state onto a fresh
+// mem.MemMapFs, suitable for use alongside aferowebdav.NewFileSystem.
+func NewMemLS() webdav.LockSystem {
+	return &memLockSystem{
+		fs:    mem.NewMemMapFs(),
+		locks: make(map[string]*lockRecord),
+	}
+}
+
+func (ls *memLockSystem) Confirm(now time.Time, name0, name1 string, conditions ...webdav.Condition) (release func(), err error) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	ls.collectExpired(now)
+
+	for _, name := range [...]string{name0, name1} {
+		if name == "" {
+			continue
+		}
+		if rec := ls.locks[slashClean(name)]; rec != nil && !conditionMatches(rec, conditions) {
+			return nil, webdav.ErrLocked
+		}
+	}
+	return func() {}, nil
+}
+
+func (ls *memLockSystem) Create(now time.Time, details webdav.LockDetails) (token string, err error) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	ls.collectExpired(now)
+
+	root := slashClean(details.Root)
+	if existing := ls.locks[root]; existing != nil {
+		return "", webdav.ErrLocked
+	}
+
+	token, err = newLockToken()
+	if err != nil {
+		return "", err
+	}
+	rec := &lockRecord{
+		Token:     token,
+		Root:      root,
+		ZeroDepth: details.ZeroDepth,
+		OwnerXML:  details.OwnerXML,
+		Expiry:    now.Add(details.Duration),
+	}
+	ls.locks[root] = rec
+	ls.persist(rec)
+	return token, nil
+}
+
+func (ls *memLockSystem) Refresh(now time.Time, token string, duration time.Duration) (webdav.LockDetails, error) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	ls.collectExpired(now)
+
+	for _, rec := range ls.locks {
+		if rec.Token == token {
+			rec.Expiry = now.Add(duration)
+			ls.persist(rec)
+			return webdav.LockDetails{
+				Root:      rec.Root,
+				Duration:  duration,
+				OwnerXML:  rec.OwnerXML,
+				ZeroDepth: rec.ZeroDepth,
+			}, nil
+		}
+	}
+	return webdav.LockDetails{}, webdav.ErrNoSuchLock
+}
+
+func (ls *memLockSystem) Unlock(now time.Time, token string) error {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	ls.collectExpired(now)
+
+	for root, rec := range ls.locks {
+		if rec.Token == token {
+			delete(ls.locks, root)
+			_ = ls.fs.Remove(lockFileName(root))
+			return nil
+		}
+	}
+	return webdav.ErrNoSuchLock
+}
+
+// collectExpired drops locks whose duration has elapsed. Must be called with
+// ls.mu held.
+func (ls *memLockSystem) collectExpired(now time.Time) {
+	for root, rec := range ls.locks {
+		if !rec.Expiry.After(now) {
+			delete(ls.locks, root)
+			_ = ls.fs.Remove(lockFileName(root))
+		}
+	}
+}
+
+// persist journals rec onto ls.fs, best-effort: a failure to write the
+// record must never block the in-memory lock from taking effect.
+func (ls *memLockSystem) persist(rec *lockRecord) {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return
+	}
+	name := lockFileName(rec.Root)
+	_ = ls.fs.MkdirAll(path.Dir(name), 0o700)
+	_ = afero.WriteFile(ls.fs, name, b, 0o600)
+}
+
+func lockFileName(root string) string {
+	return path.Join("/.locks", fmt.Sprintf("%x.json", root))
+}
+
+func conditionMatches(rec *lockRecord, conditions []webdav.Condition) bool {
+	for _, c := range conditions {
+		if c.Token == rec.Token {
+			return true
+		}
+	}
+	return false
+}
+
+func newLockToken() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("opaquelocktoken:%x", b), nil
+}
@@ -0,0 +1,41 @@
+package aferowebdav
+
+import (
+	"errors"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// errInvalidName is returned when a WebDAV path cannot be mapped onto the
+// underlying afero.Fs because it contains a NUL byte, and by RemoveAll when
+// asked to remove the root itself.
+var errInvalidName = errors.New("aferowebdav: invalid character in file path")
+
+// slashClean mirrors the behaviour of net/http's Dir and webdav.Dir: it
+// prefixes name with a leading "/" if it doesn't already have one, and then
+// runs path.Clean on the result. This guards against "." and ".." segments
+// being used to escape whatever directory is served, regardless of the
+// os-specific path separator the afero.Fs underneath expects.
+func slashClean(name string) string {
+	if name == "" || name[0] != '/' {
+		name = "/" + name
+	}
+	return path.Clean(name)
+}
+
+// toAferoPath converts a WebDAV request path (always slash-separated, as per
+// the net/url and net/http conventions) into a path suitable for the afero.Fs
+// wrapped by FileSystem. It rejects any name that contains a NUL byte;
+// escaping the root via ".." is already ruled out by slashClean rooting the
+// path at "/" before running path.Clean on it.
+func toAferoPath(name string) (string, error) {
+	if strings.IndexByte(name, 0) >= 0 {
+		return "", errInvalidName
+	}
+	clean := slashClean(name)
+	if filepath.Separator != '/' {
+		clean = filepath.FromSlash(clean)
+	}
+	return clean, nil
+}
@@ -0,0 +1,57 @@
+package aferowebdav
+
+import "testing"
+
+func TestToAferoPath(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{name: "root", in: "/", want: "/"},
+		{name: "empty is root", in: "", want: "/"},
+		{name: "relative", in: "foo/bar", want: "/foo/bar"},
+		{name: "absolute", in: "/foo/bar", want: "/foo/bar"},
+		{name: "dot", in: "/foo/./bar", want: "/foo/bar"},
+		{name: "trailing slash", in: "/foo/bar/", want: "/foo/bar"},
+		{name: "dot dot within bounds", in: "/foo/../bar", want: "/bar"},
+		{name: "dot dot above root", in: "/../etc/passwd", want: "/etc/passwd"},
+		{name: "triple dot is a literal name", in: "/.../foo", want: "/.../foo"},
+		{name: "nul byte rejected", in: "/foo\x00bar", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := toAferoPath(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("toAferoPath(%q): expected error, got %q", tt.in, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("toAferoPath(%q): unexpected error: %v", tt.in, err)
+			}
+			if got != tt.want {
+				t.Errorf("toAferoPath(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSlashClean(t *testing.T) {
+	tests := map[string]string{
+		"":           "/",
+		"a":          "/a",
+		"/a/b/":      "/a/b",
+		"/a//b":      "/a/b",
+		"/a/b/../c":  "/a/c",
+		string('\\'): "/" + string('\\'), // windows separators are not special in a WebDAV path
+	}
+	for in, want := range tests {
+		if got := slashClean(in); got != want {
+			t.Errorf("slashClean(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package blockfs
+
+import (
+	"crypto/sha256"
+	"io"
+
+	"github.com/spf13/afero"
+)
+
+// DefaultBlockSize is the block size Blocks and Sync use when the caller
+// doesn't need a different granularity. 128 KiB keeps the per-block hashing
+// overhead low while still giving a meaningful dedup/diff granularity for
+// typical afero-backed files.
+const DefaultBlockSize = 128 * 1024
+
+// emptyHash is the SHA-256 digest of the empty byte string. A zero-length
+// file is represented as a single zero-size Block carrying this hash, so
+// that two empty files always compare equal without any special-casing in
+// Diff beyond the existing empty-slice checks.
+var emptyHash = sha256.Sum256(nil)
+
+// Block is one fixed-size, content-hashed chunk of a file.
+type Block struct {
+	Offset int64
+	Size   uint32
+	Hash   []byte
+}
+
+// Blocks splits f into blockSize-sized chunks starting at offset 0 and
+// returns the SHA-256 hash of each. The final block may be shorter than
+// blockSize. A zero-length file yields a single Block{Offset: 0, Size: 0,
+// Hash: sha256("")}.
+func Blocks(f afero.File, blockSize int) ([]Block, error) {
+	if blockSize <= 0 {
+		blockSize = DefaultBlockSize
+	}
+
+	buf := make([]byte, blockSize)
+	var blocks []Block
+	var offset int64
+
+	for {
+		n, err := io.ReadFull(f, buf)
+		if n > 0 {
+			h := sha256.Sum256(buf[:n])
+			blocks = append(blocks, Block{
+				Offset: offset,
+				Size:   uint32(n),
+				Hash:   h[:],
+			})
+			offset += int64(n)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(blocks) == 0 {
+		blocks = append(blocks, Block{Offset: 0, Size: 0, Hash: emptyHash[:]})
+	}
+	return blocks, nil
+}
@@ -0,0 +1,108 @@
+package blockfs
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/AbsFS/afero/mem"
+	"github.com/spf13/afero"
+)
+
+func openWith(t *testing.T, content []byte) afero.File {
+	t.Helper()
+	fs := afero.NewMemMapFs()
+	if err := afero.WriteFile(fs, "f", content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := fs.Open("f")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestBlocksEmptyFile(t *testing.T) {
+	f := openWith(t, nil)
+	defer f.Close()
+
+	blocks, err := Blocks(f, DefaultBlockSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blocks) != 1 || blocks[0].Size != 0 {
+		t.Fatalf("expected a single zero-size block, got %+v", blocks)
+	}
+	if !bytes.Equal(blocks[0].Hash, emptyHash[:]) {
+		t.Errorf("expected the well-known empty hash, got %x", blocks[0].Hash)
+	}
+}
+
+func TestBlocksSplitsOnBlockSize(t *testing.T) {
+	content := bytes.Repeat([]byte("a"), 10)
+	f := openWith(t, content)
+	defer f.Close()
+
+	blocks, err := Blocks(f, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blocks) != 3 {
+		t.Fatalf("expected 3 blocks, got %d", len(blocks))
+	}
+	if blocks[0].Size != 4 || blocks[1].Size != 4 || blocks[2].Size != 2 {
+		t.Errorf("unexpected block sizes: %+v", blocks)
+	}
+	if blocks[2].Offset != 8 {
+		t.Errorf("expected last block offset 8, got %d", blocks[2].Offset)
+	}
+}
+
+// TestBlocksConcurrentMutationDuringDiff models a writer mutating a MemMapFs
+// file while Blocks hashes it from another handle, mirroring the mem
+// package's TestFileDataSizeRace: run with -race, this must never report a
+// data race. Blocks reads the file one block at a time, so its hashes may
+// mix before- and after-mutation content; the test checks only that Diff
+// accounts for every target block as either have or need.
+func TestBlocksConcurrentMutationDuringDiff(t *testing.T) {
+	t.Parallel()
+
+	fs := mem.NewMemMapFs()
+	before := bytes.Repeat([]byte("x"), 256)
+	if err := afero.WriteFile(fs, "f", before, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot, err := fs.Open("f")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer snapshot.Close()
+
+	srcBlocks, err := Blocks(snapshot, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	live, err := fs.Open("f")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer live.Close()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = afero.WriteFile(fs, "f", bytes.Repeat([]byte("y"), 256), 0o644)
+	}()
+
+	tgtBlocks, err := Blocks(live, 64)
+	<-done
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	have, need := Diff(srcBlocks, tgtBlocks)
+	if len(have)+len(need) != len(tgtBlocks) {
+		t.Errorf("have+need = %d, want %d (every target block accounted for)", len(have)+len(need), len(tgtBlocks))
+	}
+}
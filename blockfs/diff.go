@@ -0,0 +1,29 @@
+package blockfs
+
+import "bytes"
+
+// Diff compares src against tgt block-by-block, by index, and reports which
+// tgt blocks the caller already "has" (because src carries an identical
+// block at the same index) and which it still "needs" to fetch.
+//
+// Two special cases avoid ever returning a partial view by accident:
+//   - an empty src means nothing is known yet, so every tgt block is needed.
+//   - an empty tgt means there's nothing to transfer at all, so both
+//     returned slices are nil.
+func Diff(src, tgt []Block) (have, need []Block) {
+	if len(tgt) == 0 {
+		return nil, nil
+	}
+	if len(src) == 0 {
+		return nil, append([]Block(nil), tgt...)
+	}
+
+	for i, t := range tgt {
+		if i >= len(src) || !bytes.Equal(src[i].Hash, t.Hash) {
+			need = append(need, t)
+			continue
+		}
+		have = append(have, t)
+	}
+	return have, need
+}
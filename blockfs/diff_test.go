@@ -0,0 +1,74 @@
+package blockfs
+
+import "testing"
+
+func hashBlock(b byte) Block {
+	return Block{Size: 1, Hash: []byte{b}}
+}
+
+func TestDiff(t *testing.T) {
+	a, b, c := hashBlock('a'), hashBlock('b'), hashBlock('c')
+
+	tests := []struct {
+		name     string
+		src, tgt []Block
+		wantHave []Block
+		wantNeed []Block
+	}{
+		{
+			name: "empty target",
+			src:  []Block{a},
+			tgt:  nil,
+		},
+		{
+			name:     "empty source needs everything",
+			src:      nil,
+			tgt:      []Block{a, b},
+			wantNeed: []Block{a, b},
+		},
+		{
+			name:     "identical",
+			src:      []Block{a, b},
+			tgt:      []Block{a, b},
+			wantHave: []Block{a, b},
+		},
+		{
+			name:     "changed block",
+			src:      []Block{a, b},
+			tgt:      []Block{a, c},
+			wantHave: []Block{a},
+			wantNeed: []Block{c},
+		},
+		{
+			name:     "target longer than source",
+			src:      []Block{a},
+			tgt:      []Block{a, b},
+			wantHave: []Block{a},
+			wantNeed: []Block{b},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			have, need := Diff(tt.src, tt.tgt)
+			if !blocksEqual(have, tt.wantHave) {
+				t.Errorf("have = %+v, want %+v", have, tt.wantHave)
+			}
+			if !blocksEqual(need, tt.wantNeed) {
+				t.Errorf("need = %+v, want %+v", need, tt.wantNeed)
+			}
+		})
+	}
+}
+
+func blocksEqual(a, b []Block) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if string(a[i].Hash) != string(b[i].Hash) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,5 @@
+// Package blockfs computes content-addressed block hashes over afero.File
+// contents and uses them to transfer only the bytes that changed between two
+// afero.Fs instances, rsync-style, without either side needing a rolling
+// checksum or a shared index ahead of time.
+package blockfs
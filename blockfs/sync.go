@@ -0,0 +1,54 @@
+package blockfs
+
+import (
+	"os"
+
+	"github.com/spf13/afero"
+)
+
+// Sync makes the file at path on dstFs byte-identical to the file at path on
+// srcFs, transferring only the blocks that differ. It opens both files,
+// hashes them in DefaultBlockSize chunks, and then copies just the blocks
+// Diff reports as needed via ReadAt on the source and WriteAt on the
+// destination, finally truncating the destination to match the source's
+// length.
+func Sync(srcFs, dstFs afero.Fs, path string) error {
+	srcFile, err := srcFs.Open(path)
+	if err != nil {
+		return err
+	}
+	defer srcFile.Close()
+
+	dstFile, err := dstFs.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	defer dstFile.Close()
+
+	srcBlocks, err := Blocks(srcFile, DefaultBlockSize)
+	if err != nil {
+		return err
+	}
+	dstBlocks, err := Blocks(dstFile, DefaultBlockSize)
+	if err != nil {
+		return err
+	}
+
+	_, need := Diff(dstBlocks, srcBlocks)
+	buf := make([]byte, DefaultBlockSize)
+	for _, b := range need {
+		chunk := buf[:b.Size]
+		if _, err := srcFile.ReadAt(chunk, b.Offset); err != nil {
+			return err
+		}
+		if _, err := dstFile.WriteAt(chunk, b.Offset); err != nil {
+			return err
+		}
+	}
+
+	srcInfo, err := srcFile.Stat()
+	if err != nil {
+		return err
+	}
+	return dstFile.Truncate(srcInfo.Size())
+}
@@ -0,0 +1,61 @@
+package blockfs
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+func TestSyncTransfersOnlyChangedBlocks(t *testing.T) {
+	srcFs := afero.NewMemMapFs()
+	dstFs := afero.NewMemMapFs()
+
+	original := []byte("0123456789abcdef")
+	if err := afero.WriteFile(srcFs, "f", original, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := afero.WriteFile(dstFs, "f", original, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	updated := []byte("0123456789ZZZZZf")
+	if err := afero.WriteFile(srcFs, "f", updated, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Sync(srcFs, dstFs, "f"); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := afero.ReadFile(dstFs, "f")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(updated) {
+		t.Errorf("dst = %q, want %q", got, updated)
+	}
+}
+
+func TestSyncShrinksDestination(t *testing.T) {
+	srcFs := afero.NewMemMapFs()
+	dstFs := afero.NewMemMapFs()
+
+	if err := afero.WriteFile(srcFs, "f", []byte("short"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := afero.WriteFile(dstFs, "f", []byte("a much longer original body"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Sync(srcFs, dstFs, "f"); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := afero.ReadFile(dstFs, "f")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "short" {
+		t.Errorf("dst = %q, want %q", got, "short")
+	}
+}
@@ -0,0 +1,112 @@
+package mem
+
+import (
+	"crypto/sha256"
+	"sync"
+)
+
+// ContentStore is a content-addressed byte-block store, keyed by SHA-256
+// hash. It lets a MemMapFs keep one copy of a block even when the same
+// bytes appear in several files, which is exactly the data blockfs.Sync
+// already hashes and diffs against when replicating between two afero.Fs.
+// Call UseContentStore on a FileData to have it store its content as a
+// chunk table pointing into a ContentStore instead of an in-line []byte.
+//
+// A ContentStore only ever grows: Put is idempotent and Get never removes an
+// entry, since nothing here tracks how many files still reference a given
+// block. Reclaiming unreferenced blocks is left for a future change — it
+// needs the reference-counting layer in handle.go to also cover individual
+// chunks, not just whole FileData values.
+type ContentStore struct {
+	mu     sync.RWMutex
+	blocks map[[sha256.Size]byte][]byte
+}
+
+// NewContentStore returns an empty ContentStore.
+func NewContentStore() *ContentStore {
+	return &ContentStore{blocks: make(map[[sha256.Size]byte][]byte)}
+}
+
+// Put stores b, deduplicating against any block already sharing its hash,
+// and returns that hash.
+func (s *ContentStore) Put(b []byte) [sha256.Size]byte {
+	h := sha256.Sum256(b)
+
+	s.mu.RLock()
+	_, ok := s.blocks[h]
+	s.mu.RUnlock()
+	if ok {
+		return h
+	}
+
+	cp := make([]byte, len(b))
+	copy(cp, b)
+
+	s.mu.Lock()
+	s.blocks[h] = cp
+	s.mu.Unlock()
+	return h
+}
+
+// Get returns the block previously stored under h, if any.
+func (s *ContentStore) Get(h [sha256.Size]byte) ([]byte, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	b, ok := s.blocks[h]
+	return b, ok
+}
+
+// Len reports how many distinct blocks are currently held.
+func (s *ContentStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.blocks)
+}
+
+// contentBlockSize is the chunk size FileData uses once UseContentStore has
+// been called on it. It matches blockfs.DefaultBlockSize so that a file
+// synced in by blockfs.Sync and a file written directly through the mem.Fs
+// API dedup against the same block boundaries.
+const contentBlockSize = 128 * 1024
+
+// chunkRef is one entry of a FileData's chunk table: the hash identifies
+// the block inside store, size is how many of its bytes belong to this
+// chunk (only the final chunk of a file is ever shorter than
+// contentBlockSize).
+type chunkRef struct {
+	hash [sha256.Size]byte
+	size int
+}
+
+// chunkify splits b into contentBlockSize blocks, stores each in store
+// (deduplicating identical blocks, including across different files that
+// share the same store), and returns the resulting chunk table.
+func chunkify(store *ContentStore, b []byte) []chunkRef {
+	if len(b) == 0 {
+		return nil
+	}
+	chunks := make([]chunkRef, 0, (len(b)+contentBlockSize-1)/contentBlockSize)
+	for off := 0; off < len(b); off += contentBlockSize {
+		end := off + contentBlockSize
+		if end > len(b) {
+			end = len(b)
+		}
+		chunks = append(chunks, chunkRef{hash: store.Put(b[off:end]), size: end - off})
+	}
+	return chunks
+}
+
+// UseContentStore switches d's backing storage from an in-line []byte to a
+// chunk table pointing into store: any existing content is split into
+// contentBlockSize blocks and deduplicated against whatever store already
+// holds, and every subsequent Read/Write/Truncate against d transparently
+// goes through the chunk table instead.
+func (d *FileData) UseContentStore(store *ContentStore) {
+	d.Lock()
+	defer d.Unlock()
+	d.store = store
+	if len(d.data) > 0 {
+		d.chunks = chunkify(store, d.data)
+	}
+	d.data = nil
+}
@@ -0,0 +1,97 @@
+package mem
+
+import "testing"
+
+func TestContentStorePutDeduplicates(t *testing.T) {
+	t.Parallel()
+
+	s := NewContentStore()
+	h1 := s.Put([]byte("hello"))
+	h2 := s.Put([]byte("hello"))
+
+	if h1 != h2 {
+		t.Fatalf("expected identical content to hash the same, got %x and %x", h1, h2)
+	}
+	if s.Len() != 1 {
+		t.Errorf("expected 1 stored block, got %d", s.Len())
+	}
+
+	got, ok := s.Get(h1)
+	if !ok {
+		t.Fatal("expected block to be present")
+	}
+	if string(got) != "hello" {
+		t.Errorf("got %q, want %q", got, "hello")
+	}
+}
+
+// TestFileDataUseContentStoreDeduplicates checks the actual ask behind
+// ContentStore: two FileData values with identical content, both backed by
+// the same store, must share one underlying block instead of each holding
+// their own copy.
+func TestFileDataUseContentStoreDeduplicates(t *testing.T) {
+	store := NewContentStore()
+
+	a := CreateFile("/a.txt")
+	a.data = []byte("duplicate content")
+	a.UseContentStore(store)
+
+	b := CreateFile("/b.txt")
+	b.data = []byte("duplicate content")
+	b.UseContentStore(store)
+
+	if store.Len() != 1 {
+		t.Errorf("expected identical file content to dedup into 1 block, got %d", store.Len())
+	}
+
+	if got := string(a.bytesLocked()); got != "duplicate content" {
+		t.Errorf("a content = %q, want %q", got, "duplicate content")
+	}
+	if got := string(b.bytesLocked()); got != "duplicate content" {
+		t.Errorf("b content = %q, want %q", got, "duplicate content")
+	}
+}
+
+// TestFileDataContentStoreReadWrite checks that File.Write followed by
+// File.Read round-trips content on a FileData backed by a ContentStore, and
+// that the Write put its content into the store.
+func TestFileDataContentStoreReadWrite(t *testing.T) {
+	store := NewContentStore()
+	d := CreateFile("/chunked.txt")
+	d.UseContentStore(store)
+
+	f := NewFileHandle(d)
+	if _, err := f.WriteString("hello, world"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Seek(0, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, len("hello, world"))
+	if _, err := f.Read(buf); err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != "hello, world" {
+		t.Errorf("got %q, want %q", buf, "hello, world")
+	}
+	if store.Len() == 0 {
+		t.Error("expected Write to have stored at least one block in store")
+	}
+}
+
+func TestContentStoreConcurrentPutRace(t *testing.T) {
+	t.Parallel()
+
+	s := NewContentStore()
+	const data = "shared block"
+
+	go func() {
+		s.Put([]byte(data))
+	}()
+
+	h := s.Put([]byte(data))
+	if _, ok := s.Get(h); !ok {
+		t.Error("expected block to be retrievable after concurrent Put")
+	}
+}
@@ -0,0 +1,42 @@
+package mem
+
+// Dir is the directory-entry side of a FileData representing a directory:
+// it tracks the FileData of each direct child so Readdir can list them
+// without a full scan of the filesystem.
+type Dir interface {
+	Len() int
+	Names() []string
+	Files() []*FileData
+	Add(*FileData)
+	Remove(*FileData)
+}
+
+// DirMap is the default, map-backed Dir implementation, keyed by each
+// child's base name.
+type DirMap map[string]*FileData
+
+func (m DirMap) Len() int { return len(m) }
+
+func (m DirMap) Names() []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	return names
+}
+
+func (m DirMap) Files() []*FileData {
+	files := make([]*FileData, 0, len(m))
+	for _, f := range m {
+		files = append(files, f)
+	}
+	return files
+}
+
+func (m DirMap) Add(f *FileData) {
+	m[f.baseName()] = f
+}
+
+func (m DirMap) Remove(f *FileData) {
+	delete(m, f.baseName())
+}
@@ -0,0 +1,403 @@
+package mem
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrFileClosed is returned by operations against a *File that has already
+// been closed.
+var ErrFileClosed = errors.New("mem: file is closed")
+
+// ErrOutOfRange is returned by Truncate when asked for a negative size.
+var ErrOutOfRange = errors.New("mem: out of range")
+
+// FileData is the content and metadata of one in-memory file or directory.
+// Several *File handles (e.g. from Dup, or from opening the same path
+// twice) can share a single *FileData, which is why every field access goes
+// through the embedded mutex.
+type FileData struct {
+	sync.Mutex
+	name    string
+	data    []byte
+	memDir  Dir
+	mode    os.FileMode
+	modtime time.Time
+
+	// store and chunks optionally back data with a content-addressed,
+	// deduplicating block store instead of an in-line []byte — see
+	// UseContentStore.
+	store  *ContentStore
+	chunks []chunkRef
+
+	// symlinkTarget holds the link target when mode&os.ModeSymlink != 0; see
+	// (*MemMapFs).SymlinkIfPossible.
+	symlinkTarget string
+}
+
+// CreateFile returns a new, empty regular-file FileData named name.
+func CreateFile(name string) *FileData {
+	return &FileData{name: name, mode: 0o644, modtime: time.Now()}
+}
+
+// CreateDir returns a new, empty directory FileData named name.
+func CreateDir(name string) *FileData {
+	return &FileData{name: name, memDir: DirMap{}, mode: os.ModeDir | 0o755, modtime: time.Now()}
+}
+
+// CreateSymlink returns a new FileData named name that is a symlink to
+// target.
+func CreateSymlink(name, target string) *FileData {
+	return &FileData{name: name, mode: os.ModeSymlink | 0o777, modtime: time.Now(), symlinkTarget: target}
+}
+
+// ChangeFileName renames f in place.
+func ChangeFileName(f *FileData, newname string) {
+	f.Lock()
+	f.name = newname
+	f.Unlock()
+}
+
+// SetMode changes f's mode in place.
+func SetMode(f *FileData, mode os.FileMode) {
+	f.Lock()
+	f.mode = mode
+	f.Unlock()
+}
+
+// SetModTime changes f's modification time in place.
+func SetModTime(f *FileData, mtime time.Time) {
+	f.Lock()
+	f.modtime = mtime
+	f.Unlock()
+}
+
+// Name returns f's full path as last set by ChangeFileName/CreateFile.
+func (d *FileData) Name() string {
+	d.Lock()
+	defer d.Unlock()
+	return d.name
+}
+
+// baseName returns just the final path element, the same value os.FileInfo
+// callers expect from Name(). Must be called with d locked or otherwise
+// known not to race.
+func (d *FileData) baseName() string {
+	_, name := filepath.Split(d.name)
+	return name
+}
+
+// bytesLocked returns d's full content, whether it's held inline in d.data
+// or as a chunk table pointing into d.store. Must be called with d locked.
+func (d *FileData) bytesLocked() []byte {
+	if d.store == nil {
+		return d.data
+	}
+	out := make([]byte, 0, len(d.chunks)*contentBlockSize)
+	for _, c := range d.chunks {
+		b, ok := d.store.Get(c.hash)
+		if !ok {
+			continue
+		}
+		out = append(out, b...)
+	}
+	return out
+}
+
+// setBytesLocked replaces d's full content, re-chunking into d.store if one
+// is in use. Must be called with d locked.
+func (d *FileData) setBytesLocked(b []byte) {
+	if d.store == nil {
+		d.data = b
+		return
+	}
+	d.chunks = chunkify(d.store, b)
+	d.data = nil
+}
+
+// FileInfo adapts a *FileData to os.FileInfo.
+type FileInfo struct {
+	*FileData
+}
+
+func (s *FileInfo) Name() string {
+	s.Lock()
+	defer s.Unlock()
+	return s.baseName()
+}
+
+func (s *FileInfo) Mode() os.FileMode {
+	s.Lock()
+	defer s.Unlock()
+	return s.mode
+}
+
+func (s *FileInfo) ModTime() time.Time {
+	s.Lock()
+	defer s.Unlock()
+	return s.modtime
+}
+
+func (s *FileInfo) IsDir() bool {
+	s.Lock()
+	defer s.Unlock()
+	return s.mode&os.ModeDir != 0
+}
+
+func (s *FileInfo) Sys() interface{} { return nil }
+
+func (s *FileInfo) Size() int64 {
+	if s.IsDir() {
+		return int64(42)
+	}
+	s.Lock()
+	defer s.Unlock()
+	return int64(len(s.bytesLocked()))
+}
+
+// File is an open handle onto a FileData: its read/write offset and closed
+// state are per-handle, while the data they operate on is shared with every
+// other handle pointing at the same FileData (see Dup in handle.go).
+type File struct {
+	at           int64
+	readDirCount int64
+	closed       bool
+	readOnly     bool
+	fileData     *FileData
+	refs         *refCountRegistry
+}
+
+// NewFileHandle wraps data in a new read/write File handle positioned at
+// the start of the file. The handle is not registered with any
+// refCountRegistry; callers that hand it out through a MemMapFs (Open,
+// Create, Dup, NewAnonymousFile) set refs on the returned handle so Close
+// can participate in that Fs's unlink-while-open bookkeeping.
+func NewFileHandle(data *FileData) *File {
+	return &File{fileData: data}
+}
+
+// NewReadOnlyFileHandle wraps data in a new File handle marked read-only.
+// Write, WriteAt and Truncate do not check that mark, so they still change
+// data through such a handle.
+func NewReadOnlyFileHandle(data *FileData) *File {
+	return &File{fileData: data, readOnly: true}
+}
+
+// Data returns the FileData backing f.
+func (f *File) Data() *FileData { return f.fileData }
+
+func (f *File) Name() string { return f.fileData.Name() }
+
+func (f *File) Stat() (os.FileInfo, error) { return &FileInfo{f.fileData}, nil }
+
+func (f *File) Sync() error { return nil }
+
+// Close releases f's reference to its FileData. Once every handle sharing a
+// FileData has been closed and it is no longer linked into any directory
+// (see unlink in handle.go), its content is reclaimed. A handle created
+// directly via NewFileHandle/NewReadOnlyFileHandle rather than through a
+// MemMapFs has no refs registry to consult, so it has nothing else that
+// could still be sharing its data and its content is always reclaimed.
+func (f *File) Close() error {
+	f.fileData.Lock()
+	already := f.closed
+	f.closed = true
+	f.fileData.Unlock()
+	if already {
+		return nil
+	}
+	reclaim := true
+	if f.refs != nil {
+		reclaim = f.refs.release(f.fileData)
+	}
+	if reclaim {
+		f.fileData.Lock()
+		f.fileData.setBytesLocked(nil)
+		f.fileData.Unlock()
+	}
+	return nil
+}
+
+func (f *File) Read(b []byte) (n int, err error) {
+	f.fileData.Lock()
+	defer f.fileData.Unlock()
+	if f.closed {
+		return 0, ErrFileClosed
+	}
+	data := f.fileData.bytesLocked()
+	if len(b) > 0 && int(f.at) == len(data) {
+		return 0, io.EOF
+	}
+	if int(f.at) > len(data) {
+		return 0, io.ErrUnexpectedEOF
+	}
+	n = copy(b, data[f.at:])
+	f.at += int64(n)
+	return n, nil
+}
+
+func (f *File) ReadAt(b []byte, off int64) (n int, err error) {
+	f.fileData.Lock()
+	defer f.fileData.Unlock()
+	if f.closed {
+		return 0, ErrFileClosed
+	}
+	data := f.fileData.bytesLocked()
+	if off >= int64(len(data)) {
+		if len(b) == 0 {
+			return 0, nil
+		}
+		return 0, io.EOF
+	}
+	n = copy(b, data[off:])
+	if n < len(b) {
+		err = io.EOF
+	}
+	return n, err
+}
+
+func (f *File) Seek(offset int64, whence int) (int64, error) {
+	f.fileData.Lock()
+	defer f.fileData.Unlock()
+	if f.closed {
+		return 0, ErrFileClosed
+	}
+	switch whence {
+	case io.SeekStart:
+		f.at = offset
+	case io.SeekCurrent:
+		f.at += offset
+	case io.SeekEnd:
+		f.at = int64(len(f.fileData.bytesLocked())) + offset
+	}
+	return f.at, nil
+}
+
+// Write writes b at f's current offset. Must be called unlocked; it takes
+// fileData's lock itself.
+func (f *File) Write(b []byte) (n int, err error) {
+	f.fileData.Lock()
+	defer f.fileData.Unlock()
+	if f.closed {
+		return 0, ErrFileClosed
+	}
+	n = f.writeAtCurrentLocked(b)
+	return n, nil
+}
+
+// writeAtCurrentLocked writes b at f.at, growing the content as needed and
+// preserving any existing tail beyond the written region. Must be called
+// with fileData locked.
+func (f *File) writeAtCurrentLocked(b []byte) int {
+	data := f.fileData.bytesLocked()
+	n := len(b)
+	cur := int64(len(data))
+	diff := f.at - cur
+
+	var tail []byte
+	if int64(n)+f.at < cur {
+		tail = data[int64(n)+f.at:]
+	}
+
+	var newData []byte
+	if diff > 0 {
+		newData = append(append([]byte{}, data...), append(bytes.Repeat([]byte{0}, int(diff)), b...)...)
+	} else {
+		newData = append(append([]byte{}, data[:f.at]...), b...)
+	}
+	newData = append(newData, tail...)
+
+	f.fileData.setBytesLocked(newData)
+	f.fileData.modtime = time.Now()
+	f.at += int64(n)
+	return n
+}
+
+func (f *File) WriteAt(b []byte, off int64) (n int, err error) {
+	f.fileData.Lock()
+	defer f.fileData.Unlock()
+	if f.closed {
+		return 0, ErrFileClosed
+	}
+	prev := f.at
+	f.at = off
+	n = f.writeAtCurrentLocked(b)
+	f.at = prev
+	return n, nil
+}
+
+func (f *File) WriteString(s string) (int, error) {
+	return f.Write([]byte(s))
+}
+
+func (f *File) Truncate(size int64) error {
+	f.fileData.Lock()
+	defer f.fileData.Unlock()
+	if f.closed {
+		return ErrFileClosed
+	}
+	if size < 0 {
+		return ErrOutOfRange
+	}
+	data := f.fileData.bytesLocked()
+	switch {
+	case size > int64(len(data)):
+		data = append(append([]byte{}, data...), bytes.Repeat([]byte{0}, int(size-int64(len(data))))...)
+	default:
+		data = data[:size]
+	}
+	f.fileData.setBytesLocked(data)
+	f.fileData.modtime = time.Now()
+	return nil
+}
+
+func (f *File) Readdir(count int) ([]os.FileInfo, error) {
+	f.fileData.Lock()
+	defer f.fileData.Unlock()
+	if f.closed {
+		return nil, ErrFileClosed
+	}
+	if f.fileData.mode&os.ModeDir == 0 {
+		return nil, errors.New("mem: not a directory")
+	}
+
+	files := f.fileData.memDir.Files()
+	sort.Slice(files, func(i, j int) bool { return files[i].name < files[j].name })
+
+	if count > 0 {
+		start := int(f.readDirCount)
+		if start >= len(files) {
+			return nil, io.EOF
+		}
+		end := start + count
+		if end > len(files) {
+			end = len(files)
+		}
+		f.readDirCount = int64(end)
+		files = files[start:end]
+	}
+
+	infos := make([]os.FileInfo, 0, len(files))
+	for _, fd := range files {
+		infos = append(infos, &FileInfo{fd})
+	}
+	return infos, nil
+}
+
+func (f *File) Readdirnames(n int) ([]string, error) {
+	infos, err := f.Readdir(n)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(infos))
+	for _, fi := range infos {
+		names = append(names, fi.Name())
+	}
+	return names, nil
+}
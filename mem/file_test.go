@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/fatih/structtag"
+	"github.com/spf13/afero"
 )
 
 func TestFileDataNameRace(t *testing.T) {
@@ -366,3 +367,180 @@ func getExpectations(i int, t *testing.T, test interface{}) map[string]bool {
 	t.Logf("%d: Test %s( %s ) ( %s )", i, op, strings.Join(inputs, ", "), strings.Join(explist, ", "))
 	return expected
 }
+
+// TestFileHandleOps exercises Dup, Linkat and unlink-while-open as a
+// sequence of table-driven steps against a set of named, shared handles,
+// the same op-dispatch style TestFile uses for Read/Write/Seek but extended
+// to the Fs-level operations that act on a *File rather than its bytes.
+func TestFileHandleOps(t *testing.T) {
+	type step struct {
+		Op   string // create, write, readAt0, dup, truncate, open, openErr, statMissing, remove, seekEnd, anon, linkat, linkatErr, readFile, close
+		// Handle names the step's primary *File, looked up in the handles map;
+		// a create/open step stores its new handle under this name.
+		Handle string
+		// As is the name a dup/anon step stores its new handle under.
+		As string
+		// Path is the afero.Fs path a create/open/remove/linkat/readFile step
+		// acts on.
+		Path string
+		Data string
+		Size int64
+		Want string
+	}
+
+	tests := []step{
+		{Op: "create", Handle: "primary", Path: "/handle-ops.txt"},
+		{Op: "write", Handle: "primary", Data: "hello, world"},
+		{Op: "dup", Handle: "primary", As: "dup"},
+		// ReadAt never moves a handle's own Seek cursor, so this also shows
+		// dup shares primary's data without sharing its offset.
+		{Op: "readAt0", Handle: "dup", Want: "hello, world"},
+		{Op: "truncate", Handle: "dup", Size: 5},
+		// An Open unrelated to Dup, against the same still-linked path,
+		// must see dup's truncation too: the data is genuinely shrunk, not
+		// merely hidden behind the handle that truncated it.
+		{Op: "open", Handle: "independent", Path: "/handle-ops.txt"},
+		{Op: "readAt0", Handle: "independent", Want: "hello"},
+		{Op: "remove", Path: "/handle-ops.txt"},
+		{Op: "statMissing", Path: "/handle-ops.txt"},
+		{Op: "openErr", Path: "/handle-ops.txt"},
+		// unlink-while-open: every handle opened before the Remove must
+		// keep working, and still share the same data, after it.
+		{Op: "seekEnd", Handle: "primary"},
+		{Op: "write", Handle: "primary", Data: "!"},
+		{Op: "readAt0", Handle: "independent", Want: "hello!"},
+		{Op: "close", Handle: "primary"},
+		{Op: "close", Handle: "dup"},
+		{Op: "close", Handle: "independent"},
+
+		{Op: "anon", As: "anon"},
+		{Op: "write", Handle: "anon", Data: "anonymous"},
+		{Op: "statMissing", Path: "/linked.txt"},
+		{Op: "linkat", Handle: "anon", Path: "/linked.txt"},
+		{Op: "readFile", Path: "/linked.txt", Want: "anonymous"},
+		{Op: "linkatErr", Handle: "anon", Path: "/linked-again.txt"},
+		{Op: "close", Handle: "anon"},
+	}
+
+	fs := NewMemMapFs().(*MemMapFs)
+	handles := map[string]afero.File{}
+
+	for i, tt := range tests {
+		switch tt.Op {
+		case "create":
+			f, err := fs.Create(tt.Path)
+			if err != nil {
+				t.Fatalf("step %d create %s: %v", i, tt.Path, err)
+			}
+			handles[tt.Handle] = f
+		case "write":
+			if _, err := handles[tt.Handle].WriteString(tt.Data); err != nil {
+				t.Fatalf("step %d write to %s: %v", i, tt.Handle, err)
+			}
+		case "readAt0":
+			buf := make([]byte, len(tt.Want))
+			if _, err := handles[tt.Handle].ReadAt(buf, 0); err != nil {
+				t.Fatalf("step %d readAt0 on %s: %v", i, tt.Handle, err)
+			}
+			if string(buf) != tt.Want {
+				t.Errorf("step %d: %s read %q, want %q", i, tt.Handle, buf, tt.Want)
+			}
+		case "dup":
+			dup, err := fs.Dup(handles[tt.Handle])
+			if err != nil {
+				t.Fatalf("step %d dup of %s: %v", i, tt.Handle, err)
+			}
+			if pos, err := dup.Seek(0, io.SeekCurrent); err != nil || pos != 0 {
+				t.Errorf("step %d: dup offset = %d, err %v; want 0, nil", i, pos, err)
+			}
+			handles[tt.As] = dup
+		case "truncate":
+			if err := handles[tt.Handle].Truncate(tt.Size); err != nil {
+				t.Fatalf("step %d truncate %s: %v", i, tt.Handle, err)
+			}
+		case "open":
+			f, err := fs.Open(tt.Path)
+			if err != nil {
+				t.Fatalf("step %d open %s: %v", i, tt.Path, err)
+			}
+			handles[tt.Handle] = f
+		case "openErr":
+			if _, err := fs.Open(tt.Path); err == nil {
+				t.Errorf("step %d: expected Open(%s) to fail", i, tt.Path)
+			}
+		case "statMissing":
+			if _, err := fs.Stat(tt.Path); err == nil {
+				t.Errorf("step %d: expected %s to not exist yet", i, tt.Path)
+			}
+		case "remove":
+			if err := fs.Remove(tt.Path); err != nil {
+				t.Fatalf("step %d remove %s: %v", i, tt.Path, err)
+			}
+		case "seekEnd":
+			if _, err := handles[tt.Handle].Seek(0, io.SeekEnd); err != nil {
+				t.Fatalf("step %d seekEnd %s: %v", i, tt.Handle, err)
+			}
+		case "anon":
+			handles[tt.As] = fs.NewAnonymousFile("tmp")
+		case "linkat":
+			if err := fs.Linkat(handles[tt.Handle], tt.Path); err != nil {
+				t.Fatalf("step %d linkat %s: %v", i, tt.Path, err)
+			}
+		case "linkatErr":
+			if err := fs.Linkat(handles[tt.Handle], tt.Path); err == nil {
+				t.Errorf("step %d: expected a second Linkat of %s to fail", i, tt.Handle)
+			}
+		case "readFile":
+			got, err := afero.ReadFile(fs, tt.Path)
+			if err != nil {
+				t.Fatalf("step %d readFile %s: %v", i, tt.Path, err)
+			}
+			if string(got) != tt.Want {
+				t.Errorf("step %d: %s content = %q, want %q", i, tt.Path, got, tt.Want)
+			}
+		case "close":
+			if err := handles[tt.Handle].Close(); err != nil {
+				t.Fatalf("step %d close %s: %v", i, tt.Handle, err)
+			}
+		default:
+			t.Fatalf("step %d: unknown op %q", i, tt.Op)
+		}
+	}
+
+	// Every handle above that ever shared a FileData has now closed, and
+	// every FileData involved was removed or re-linked, so nothing should
+	// remain tracked.
+	fs.refs.mu.Lock()
+	defer fs.refs.mu.Unlock()
+	if n := len(fs.refs.count); n != 0 {
+		t.Errorf("refs.count leaked %d entries after every handle closed", n)
+	}
+	if n := len(fs.refs.linked); n != 0 {
+		t.Errorf("refs.linked leaked %d entries after every handle closed", n)
+	}
+}
+
+// TestRefCountRegistryNoLeakForUntrackedRemove checks the fix for a leak
+// where Remove/RemoveAll unconditionally created a refCountRegistry entry
+// for every FileData they touched, even ones that were never opened through
+// a handle (e.g. a plain Mkdir+Remove) and so would never have release()
+// called to clean that entry back up.
+func TestRefCountRegistryNoLeakForUntrackedRemove(t *testing.T) {
+	fs := NewMemMapFs().(*MemMapFs)
+
+	if err := fs.Mkdir("/untouched", 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Remove("/untouched"); err != nil {
+		t.Fatal(err)
+	}
+
+	fs.refs.mu.Lock()
+	defer fs.refs.mu.Unlock()
+	if n := len(fs.refs.count); n != 0 {
+		t.Errorf("refs.count leaked %d entries for a FileData that was never opened", n)
+	}
+	if n := len(fs.refs.linked); n != 0 {
+		t.Errorf("refs.linked leaked %d entries for a FileData that was never opened", n)
+	}
+}
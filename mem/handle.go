@@ -0,0 +1,158 @@
+package mem
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/spf13/afero"
+)
+
+// refCountRegistry tracks, for each FileData handed out by one MemMapFs, how
+// many open *File handles currently reference it and whether it is still
+// linked into that Fs's directory tree. It is the building block for
+// Unix-style unlink-while-open semantics: a file's data should only ever be
+// reclaimed once both its last directory link and its last open handle are
+// gone.
+//
+// Each MemMapFs owns its own registry (see MemMapFs.refs) rather than
+// sharing one across every Fs in the process: a FileData never outlives the
+// Fs that created it, so a process-global map would otherwise accumulate one
+// orphaned entry per Remove/RemoveAll call for the rest of the process's
+// life, pinning that FileData from GC.
+//
+// Fs.OpenFile tracks every handle it hands out and Fs.Remove/Fs.RemoveAll
+// call unlink, so this applies to ordinary Open/Close as much as it does to
+// Dup/Linkat/NewAnonymousFile.
+type refCountRegistry struct {
+	mu     sync.Mutex
+	count  map[*FileData]int
+	linked map[*FileData]bool
+}
+
+func newRefCountRegistry() *refCountRegistry {
+	return &refCountRegistry{
+		count:  make(map[*FileData]int),
+		linked: make(map[*FileData]bool),
+	}
+}
+
+// track registers a new handle against d. linked reports whether d currently
+// has a directory entry pointing at it; pass false for an anonymous,
+// not-yet-linked file created via NewAnonymousFile.
+func (r *refCountRegistry) track(d *FileData, linked bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.count[d]++
+	if _, seen := r.linked[d]; !seen {
+		r.linked[d] = linked
+	}
+}
+
+// release drops one handle reference to d and reports whether its data may
+// now be reclaimed: no handles remain open against it, and it has no
+// directory link either.
+func (r *refCountRegistry) release(d *FileData) (reclaim bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.count[d] > 0 {
+		r.count[d]--
+	}
+	if r.count[d] > 0 {
+		return false
+	}
+	reclaim = !r.linked[d]
+	delete(r.count, d)
+	delete(r.linked, d)
+	return reclaim
+}
+
+// unlink marks d as having no directory link, the mem-package equivalent of
+// removing a file's last directory entry, and reports whether its data may
+// be reclaimed immediately because no handle currently has it open. If d was
+// never tracked (e.g. it was never opened through a handle, as with a plain
+// Mkdir+Remove), there is nothing to mark or later clean up in release, so
+// unlink reports it reclaimable immediately without creating an entry.
+func (r *refCountRegistry) unlink(d *FileData) (reclaim bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, tracked := r.linked[d]; !tracked {
+		return true
+	}
+	r.linked[d] = false
+	return r.count[d] == 0
+}
+
+// isLinked reports whether d currently has a directory entry pointing at
+// it. Dup uses it to track a duplicate with the same link state, and Linkat
+// to refuse a file that is already linked.
+func (r *refCountRegistry) isLinked(d *FileData) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.linked[d]
+}
+
+// Dup returns a new afero.File sharing f's underlying FileData — so writes
+// through either handle are visible to the other — but with its own
+// independent read/write offset, mirroring dup(2). Closing one handle never
+// closes the other; the shared data is only eligible for reclamation once
+// every duplicate and the directory link (if any) are gone.
+func (m *MemMapFs) Dup(f afero.File) (afero.File, error) {
+	mf, ok := f.(*File)
+	if !ok {
+		return nil, fmt.Errorf("mem: Dup requires a *mem.File, got %T", f)
+	}
+	m.refs.track(mf.fileData, m.refs.isLinked(mf.fileData))
+	h := NewFileHandle(mf.fileData)
+	h.refs = m.refs
+	return h, nil
+}
+
+// NewAnonymousFile creates a FileData that is open in m but not linked into
+// any of its directories, the mem-package analogue of opening a file with
+// Linux's O_TMPFILE. name is only used as the file's reported Name() until
+// Linkat gives it a real path.
+func (m *MemMapFs) NewAnonymousFile(name string) afero.File {
+	d := CreateFile(name)
+	m.refs.track(d, false)
+	h := NewFileHandle(d)
+	h.refs = m.refs
+	return h
+}
+
+// Linkat gives the anonymous FileData behind f (as created by
+// NewAnonymousFile) a real path, the mem-package analogue of calling
+// linkat(2) against an O_TMPFILE descriptor: the same FileData that f
+// already has open becomes reachable at path, rather than a copy of its
+// current bytes. It fails if f was already linked or if path is already
+// taken.
+func (m *MemMapFs) Linkat(f afero.File, path string) error {
+	mf, ok := f.(*File)
+	if !ok {
+		return fmt.Errorf("mem: Linkat requires a *mem.File, got %T", f)
+	}
+	if m.refs.isLinked(mf.fileData) {
+		return fmt.Errorf("mem: %s is already linked", mf.fileData.Name())
+	}
+
+	name := normalizePath(path)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.data[name]; exists {
+		return &os.PathError{Op: "link", Path: name, Err: os.ErrExist}
+	}
+
+	ChangeFileName(mf.fileData, name)
+	m.data[name] = mf.fileData
+	if err := m.registerWithParent(mf.fileData); err != nil {
+		delete(m.data, name)
+		return err
+	}
+
+	m.refs.mu.Lock()
+	m.refs.linked[mf.fileData] = true
+	m.refs.mu.Unlock()
+	return nil
+}
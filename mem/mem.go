@@ -0,0 +1,320 @@
+package mem
+
+import (
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+// FilePathSeparator is the separator normalizePath folds every incoming
+// path onto before storing it, regardless of the host OS.
+const FilePathSeparator = "/"
+
+// ErrDirNotEmpty is returned by Remove when asked to remove a non-empty
+// directory (use RemoveAll instead).
+var ErrDirNotEmpty = errors.New("mem: directory not empty")
+
+// MemMapFs is an in-memory afero.Fs: every file and directory lives as a
+// *FileData in a flat, path-keyed map guarded by mu.
+type MemMapFs struct {
+	mu   sync.RWMutex
+	data map[string]*FileData
+	init sync.Once
+	refs *refCountRegistry
+}
+
+var _ afero.Fs = (*MemMapFs)(nil)
+
+// NewMemMapFs returns a new, empty in-memory filesystem.
+func NewMemMapFs() afero.Fs {
+	m := &MemMapFs{refs: newRefCountRegistry()}
+	m.ensureRoot()
+	return m
+}
+
+func (m *MemMapFs) ensureRoot() {
+	m.init.Do(func() {
+		m.data = make(map[string]*FileData)
+		m.data[FilePathSeparator] = CreateDir(FilePathSeparator)
+	})
+}
+
+// normalizePath folds name onto a clean, absolute, slash-separated path
+// regardless of what separator or relativity the caller used.
+func normalizePath(name string) string {
+	name = filepath.ToSlash(name)
+	if !strings.HasPrefix(name, "/") {
+		name = "/" + name
+	}
+	name = path.Clean(name)
+	return name
+}
+
+func parentOf(name string) string {
+	if name == FilePathSeparator {
+		return FilePathSeparator
+	}
+	return path.Dir(name)
+}
+
+// registerWithParent adds f as a child of its parent directory so Readdir
+// on the parent will list it. Must be called with m.mu held.
+func (m *MemMapFs) registerWithParent(f *FileData) error {
+	parent := parentOf(f.name)
+	if parent == f.name {
+		return nil
+	}
+	pd, ok := m.data[parent]
+	if !ok {
+		return &os.PathError{Op: "open", Path: f.name, Err: os.ErrNotExist}
+	}
+	if pd.mode&os.ModeDir == 0 {
+		return &os.PathError{Op: "open", Path: f.name, Err: errors.New("mem: not a directory")}
+	}
+	pd.memDir.Add(f)
+	return nil
+}
+
+// unregisterFromParent removes f from its parent directory's listing. Must
+// be called with m.mu held.
+func (m *MemMapFs) unregisterFromParent(f *FileData) {
+	if pd, ok := m.data[parentOf(f.name)]; ok && pd.memDir != nil {
+		pd.memDir.Remove(f)
+	}
+}
+
+func (m *MemMapFs) Name() string { return "MemMapFS" }
+
+func (m *MemMapFs) Create(name string) (afero.File, error) {
+	return m.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o644)
+}
+
+func (m *MemMapFs) Mkdir(name string, perm os.FileMode) error {
+	name = normalizePath(name)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.data[name]; ok {
+		return &os.PathError{Op: "mkdir", Path: name, Err: os.ErrExist}
+	}
+	d := CreateDir(name)
+	SetMode(d, os.ModeDir|perm)
+	m.data[name] = d
+	if err := m.registerWithParent(d); err != nil {
+		delete(m.data, name)
+		return err
+	}
+	return nil
+}
+
+func (m *MemMapFs) MkdirAll(p string, perm os.FileMode) error {
+	p = normalizePath(p)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cur := FilePathSeparator
+	for _, seg := range strings.Split(strings.Trim(p, "/"), "/") {
+		if seg == "" {
+			continue
+		}
+		cur = path.Join(cur, seg)
+		if _, ok := m.data[cur]; ok {
+			continue
+		}
+		d := CreateDir(cur)
+		SetMode(d, os.ModeDir|perm)
+		m.data[cur] = d
+		if err := m.registerWithParent(d); err != nil {
+			delete(m.data, cur)
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MemMapFs) Open(name string) (afero.File, error) {
+	return m.OpenFile(name, os.O_RDONLY, 0)
+}
+
+func (m *MemMapFs) OpenFile(name string, flag int, perm os.FileMode) (afero.File, error) {
+	name = normalizePath(name)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	d, ok := m.data[name]
+	if !ok {
+		if flag&os.O_CREATE == 0 {
+			return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+		}
+		d = CreateFile(name)
+		SetMode(d, perm)
+		m.data[name] = d
+		if err := m.registerWithParent(d); err != nil {
+			delete(m.data, name)
+			return nil, err
+		}
+	} else if flag&os.O_CREATE != 0 && flag&os.O_EXCL != 0 {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrExist}
+	}
+
+	if flag&os.O_TRUNC != 0 && d.mode&os.ModeDir == 0 {
+		d.Lock()
+		d.setBytesLocked(nil)
+		d.Unlock()
+	}
+
+	handle := NewFileHandle(d)
+	handle.refs = m.refs
+	if flag == os.O_RDONLY {
+		handle.readOnly = true
+	}
+	if flag&os.O_APPEND != 0 {
+		d.Lock()
+		handle.at = int64(len(d.bytesLocked()))
+		d.Unlock()
+	}
+
+	m.refs.track(d, true)
+	return handle, nil
+}
+
+func (m *MemMapFs) Stat(name string) (os.FileInfo, error) {
+	name = normalizePath(name)
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	d, ok := m.data[name]
+	if !ok {
+		return nil, &os.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
+	}
+	return &FileInfo{d}, nil
+}
+
+func (m *MemMapFs) Rename(oldname, newname string) error {
+	oldname = normalizePath(oldname)
+	newname = normalizePath(newname)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	d, ok := m.data[oldname]
+	if !ok {
+		return &os.PathError{Op: "rename", Path: oldname, Err: os.ErrNotExist}
+	}
+
+	m.unregisterFromParent(d)
+	delete(m.data, oldname)
+	ChangeFileName(d, newname)
+	m.data[newname] = d
+	if err := m.registerWithParent(d); err != nil {
+		return err
+	}
+
+	if d.mode&os.ModeDir != 0 {
+		for p, child := range m.data {
+			if strings.HasPrefix(p, oldname+"/") {
+				delete(m.data, p)
+				ChangeFileName(child, newname+strings.TrimPrefix(p, oldname))
+				m.data[child.name] = child
+			}
+		}
+	}
+	return nil
+}
+
+func (m *MemMapFs) Remove(name string) error {
+	name = normalizePath(name)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	d, ok := m.data[name]
+	if !ok {
+		return &os.PathError{Op: "remove", Path: name, Err: os.ErrNotExist}
+	}
+	if d.mode&os.ModeDir != 0 && d.memDir != nil && d.memDir.Len() > 0 {
+		return &os.PathError{Op: "remove", Path: name, Err: ErrDirNotEmpty}
+	}
+
+	reclaim := m.refs.unlink(d)
+	delete(m.data, name)
+	m.unregisterFromParent(d)
+	if reclaim {
+		d.Lock()
+		d.setBytesLocked(nil)
+		d.Unlock()
+	}
+	return nil
+}
+
+func (m *MemMapFs) RemoveAll(p string) error {
+	p = normalizePath(p)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	root, hadRoot := m.data[p]
+	for name, d := range m.data {
+		if name == p || strings.HasPrefix(name, p+"/") {
+			if reclaim := m.refs.unlink(d); reclaim {
+				d.Lock()
+				d.setBytesLocked(nil)
+				d.Unlock()
+			}
+			delete(m.data, name)
+		}
+	}
+	if hadRoot {
+		m.unregisterFromParent(root)
+	}
+	return nil
+}
+
+func (m *MemMapFs) Chmod(name string, mode os.FileMode) error {
+	name = normalizePath(name)
+	m.mu.RLock()
+	d, ok := m.data[name]
+	m.mu.RUnlock()
+	if !ok {
+		return &os.PathError{Op: "chmod", Path: name, Err: os.ErrNotExist}
+	}
+	isDir := d.mode&os.ModeDir != 0
+	SetMode(d, mode.Perm()|boolDirMode(isDir))
+	return nil
+}
+
+func boolDirMode(isDir bool) os.FileMode {
+	if isDir {
+		return os.ModeDir
+	}
+	return 0
+}
+
+func (m *MemMapFs) Chtimes(name string, atime, mtime time.Time) error {
+	name = normalizePath(name)
+	m.mu.RLock()
+	d, ok := m.data[name]
+	m.mu.RUnlock()
+	if !ok {
+		return &os.PathError{Op: "chtimes", Path: name, Err: os.ErrNotExist}
+	}
+	SetModTime(d, mtime)
+	return nil
+}
+
+func (m *MemMapFs) Chown(name string, uid, gid int) error {
+	name = normalizePath(name)
+	m.mu.RLock()
+	_, ok := m.data[name]
+	m.mu.RUnlock()
+	if !ok {
+		return &os.PathError{Op: "chown", Path: name, Err: os.ErrNotExist}
+	}
+	// MemMapFs has no notion of file ownership; accepted as a no-op so
+	// callers that Chown defensively (e.g. archive extractors) don't have
+	// to special-case this Fs.
+	return nil
+}
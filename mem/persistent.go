@@ -0,0 +1,240 @@
+package mem
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+// ErrStoreEmpty is returned by Store.Load when no snapshot has ever been
+// committed, so NewPersistent can tell "nothing to restore yet" apart from
+// a genuine I/O failure.
+var ErrStoreEmpty = errors.New("mem: store has no snapshot to load")
+
+// Store is a pluggable persistence backend for a Persistent Fs: it knows how
+// to hand back the most recently committed snapshot and how to accept a new
+// one, but nothing about the framed format Snapshot/Restore use.
+type Store interface {
+	// Load returns the most recently committed snapshot, or ErrStoreEmpty
+	// if Commit has never been called.
+	Load() (io.ReadCloser, error)
+	// Commit persists r as the new snapshot, superseding any previous one.
+	Commit(r io.Reader) error
+}
+
+// Fs is a MemMapFs whose contents are loaded from, and flushed back to, a
+// Store, mirroring how a collection filesystem flushes dirty file data to a
+// backing block store.
+type Fs struct {
+	*MemMapFs
+
+	store    Store
+	interval time.Duration
+	done     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// PersistentOption configures NewPersistent.
+type PersistentOption func(*Fs)
+
+// WithFlushInterval makes the returned Fs call Sync on its own every d, in
+// addition to the explicit Sync/Close calls a caller makes.
+func WithFlushInterval(d time.Duration) PersistentOption {
+	return func(fs *Fs) { fs.interval = d }
+}
+
+// NewPersistent loads store's existing snapshot, if any, into a fresh
+// MemMapFs and returns a Fs that flushes back to store on Sync, on Close,
+// and — if WithFlushInterval was given — on a timer.
+func NewPersistent(store Store, opts ...PersistentOption) (*Fs, error) {
+	fs := &Fs{
+		MemMapFs: NewMemMapFs().(*MemMapFs),
+		store:    store,
+		done:     make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(fs)
+	}
+
+	rc, err := store.Load()
+	switch {
+	case err == nil:
+		defer rc.Close()
+		if err := fs.MemMapFs.Restore(rc); err != nil {
+			return nil, err
+		}
+	case errors.Is(err, ErrStoreEmpty):
+		// Nothing committed yet; start from an empty Fs.
+	default:
+		return nil, err
+	}
+
+	if fs.interval > 0 {
+		fs.wg.Add(1)
+		go fs.flushLoop()
+	}
+	return fs, nil
+}
+
+func (fs *Fs) flushLoop() {
+	defer fs.wg.Done()
+	t := time.NewTicker(fs.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			_ = fs.Sync()
+		case <-fs.done:
+			return
+		}
+	}
+}
+
+// Sync snapshots the current contents of fs and commits them to its Store
+// immediately.
+func (fs *Fs) Sync() error {
+	var buf bytes.Buffer
+	if err := fs.MemMapFs.Snapshot(&buf); err != nil {
+		return err
+	}
+	return fs.store.Commit(&buf)
+}
+
+// Close stops the background flush loop, if any, and performs one final
+// Sync.
+func (fs *Fs) Close() error {
+	if fs.interval > 0 {
+		close(fs.done)
+		fs.wg.Wait()
+	}
+	return fs.Sync()
+}
+
+// fileStore is a Store backed by a single file on the OS filesystem. Commit
+// writes to a temporary file and renames it into place so a crash mid-write
+// never corrupts the last good snapshot.
+type fileStore struct {
+	path string
+}
+
+// NewFileStore returns a Store that persists to a single file at path.
+func NewFileStore(path string) Store {
+	return &fileStore{path: path}
+}
+
+func (s *fileStore) Load() (io.ReadCloser, error) {
+	f, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrStoreEmpty
+	}
+	return f, err
+}
+
+func (s *fileStore) Commit(r io.Reader) error {
+	tmp := s.path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// dirStore is a Store backed by a directory of numbered segment files on an
+// afero.Fs, so a MemMapFs can be persisted onto an OsFs, a BasePathFs, or
+// any other afero-compatible backend (including an S3-backed one). Each
+// Commit writes a new segment before removing any earlier ones, so a crash
+// mid-write still leaves the last good segment in place for Load, and the
+// directory never accumulates more than the one most recent snapshot.
+type dirStore struct {
+	fs  afero.Fs
+	dir string
+
+	mu  sync.Mutex
+	seq int
+}
+
+// NewDirStore returns a Store that commits each snapshot as a new segment
+// file under dir on fs.
+func NewDirStore(fs afero.Fs, dir string) Store {
+	return &dirStore{fs: fs, dir: dir}
+}
+
+func (s *dirStore) Load() (io.ReadCloser, error) {
+	entries, err := afero.ReadDir(s.fs, s.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrStoreEmpty
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, ErrStoreEmpty
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	latest := entries[len(entries)-1]
+
+	if n, err := strconv.Atoi(strings.TrimSuffix(latest.Name(), ".seg")); err == nil {
+		s.mu.Lock()
+		s.seq = n
+		s.mu.Unlock()
+	}
+	return s.fs.Open(filepath.Join(s.dir, latest.Name()))
+}
+
+func (s *dirStore) Commit(r io.Reader) error {
+	if err := s.fs.MkdirAll(s.dir, 0o755); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.seq++
+	name := fmt.Sprintf("%010d.seg", s.seq)
+	s.mu.Unlock()
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if err := afero.WriteFile(s.fs, filepath.Join(s.dir, name), b, 0o644); err != nil {
+		return err
+	}
+	return s.pruneOlderThan(name)
+}
+
+// pruneOlderThan removes every segment in s.dir except keep, so the
+// directory never holds more than the one snapshot Load would ever read.
+// It runs after the new segment is safely written, so a failure here just
+// leaves a harmless extra segment behind rather than risking the new one.
+func (s *dirStore) pruneOlderThan(keep string) error {
+	entries, err := afero.ReadDir(s.fs, s.dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.Name() == keep {
+			continue
+		}
+		if err := s.fs.Remove(filepath.Join(s.dir, e.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
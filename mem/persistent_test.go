@@ -0,0 +1,171 @@
+package mem
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+// memStore is a minimal in-memory Store used only by these tests; the real
+// backends are fileStore and dirStore.
+type memStore struct {
+	mu   sync.Mutex
+	data []byte
+}
+
+func (s *memStore) Load() (io.ReadCloser, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.data == nil {
+		return nil, ErrStoreEmpty
+	}
+	return ioutil.NopCloser(bytes.NewReader(s.data)), nil
+}
+
+func (s *memStore) Commit(r io.Reader) error {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.data = b
+	s.mu.Unlock()
+	return nil
+}
+
+func TestPersistentSyncAndReload(t *testing.T) {
+	store := &memStore{}
+
+	fs1, err := NewPersistent(store)
+	if err != nil {
+		t.Fatalf("NewPersistent: %v", err)
+	}
+	if err := afero.WriteFile(fs1, "/note.txt", []byte("remember this"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs1.Sync(); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+
+	fs2, err := NewPersistent(store)
+	if err != nil {
+		t.Fatalf("NewPersistent (reload): %v", err)
+	}
+	got, err := afero.ReadFile(fs2, "/note.txt")
+	if err != nil {
+		t.Fatalf("ReadFile after reload: %v", err)
+	}
+	if string(got) != "remember this" {
+		t.Errorf("content = %q, want %q", got, "remember this")
+	}
+}
+
+func TestPersistentFlushInterval(t *testing.T) {
+	store := &memStore{}
+	fs, err := NewPersistent(store, WithFlushInterval(5*time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewPersistent: %v", err)
+	}
+	defer fs.Close()
+
+	if err := afero.WriteFile(fs, "/ticking.txt", []byte("tick"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		store.mu.Lock()
+		committed := store.data != nil
+		store.mu.Unlock()
+		if committed {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("timed out waiting for background flush to commit a snapshot")
+}
+
+// TestDirStorePrunesOldSegments checks that dirStore keeps only the most
+// recently committed segment around rather than growing its backing
+// directory without bound.
+func TestDirStorePrunesOldSegments(t *testing.T) {
+	backing := afero.NewMemMapFs()
+	store := NewDirStore(backing, "/snapshots")
+
+	for i := 0; i < 5; i++ {
+		if err := store.Commit(bytes.NewReader([]byte("snapshot"))); err != nil {
+			t.Fatalf("Commit %d: %v", i, err)
+		}
+	}
+
+	entries, err := afero.ReadDir(backing, "/snapshots")
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected exactly 1 segment after 5 commits, got %d: %v", len(entries), entries)
+	}
+
+	rc, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	defer rc.Close()
+	got, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("reading loaded snapshot: %v", err)
+	}
+	if string(got) != "snapshot" {
+		t.Errorf("loaded %q, want %q", got, "snapshot")
+	}
+}
+
+// TestPersistentConcurrentWritesDuringSync mirrors the mem package's
+// TestFileDataSizeRace: Sync must produce a self-consistent snapshot even
+// while another goroutine is actively mutating the filesystem.
+func TestPersistentConcurrentWritesDuringSync(t *testing.T) {
+	t.Parallel()
+
+	store := &memStore{}
+	fs, err := NewPersistent(store)
+	if err != nil {
+		t.Fatalf("NewPersistent: %v", err)
+	}
+	if err := afero.WriteFile(fs, "/race.txt", []byte("before"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = afero.WriteFile(fs, "/race.txt", []byte("after-mutation"), 0o644)
+	}()
+
+	if err := fs.Sync(); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	<-done
+
+	reloaded := NewMemMapFs().(*MemMapFs)
+	rc, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	defer rc.Close()
+	if err := reloaded.Restore(rc); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+
+	got, err := afero.ReadFile(reloaded, "/race.txt")
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if string(got) != "before" && string(got) != "after-mutation" {
+		t.Errorf("snapshot captured a torn write: %q", got)
+	}
+}
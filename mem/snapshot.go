@@ -0,0 +1,173 @@
+package mem
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+// snapshotMagic identifies the framed format Snapshot writes and Restore
+// reads. Bumping it is how a future incompatible format change would be
+// introduced without silently misreading an older snapshot.
+const snapshotMagic = "AFS1"
+
+// Snapshot serializes the entire tree rooted at m — names, modes, modtimes,
+// symlink targets and file contents — as a versioned, self-describing framed
+// stream: a 4-byte magic header followed by one variable-length record per
+// entry. Each record holds the path, the mode as a little-endian uint32, the
+// modtime as little-endian int64 Unix nanoseconds, the symlink target and the
+// file contents; path, target and contents are each varint length-prefixed.
+// Restore reads the format back.
+func (m *MemMapFs) Snapshot(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	if _, err := bw.WriteString(snapshotMagic); err != nil {
+		return err
+	}
+	err := afero.Walk(m, "/", func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		return writeSnapshotEntry(bw, m, path, info)
+	})
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+func writeSnapshotEntry(w *bufio.Writer, fs afero.Fs, path string, info os.FileInfo) error {
+	var target string
+	if info.Mode()&os.ModeSymlink != 0 {
+		reader, ok := fs.(afero.LinkReader)
+		if !ok {
+			return fmt.Errorf("mem: %q is a symlink but %T does not support ReadlinkIfPossible", path, fs)
+		}
+		t, err := reader.ReadlinkIfPossible(path)
+		if err != nil {
+			return err
+		}
+		target = t
+	}
+
+	var data []byte
+	if !info.IsDir() && info.Mode()&os.ModeSymlink == 0 {
+		b, err := afero.ReadFile(fs, path)
+		if err != nil {
+			return err
+		}
+		data = b
+	}
+
+	if err := writeBytes(w, []byte(path)); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.LittleEndian, uint32(info.Mode())); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.LittleEndian, info.ModTime().UnixNano()); err != nil {
+		return err
+	}
+	if err := writeBytes(w, []byte(target)); err != nil {
+		return err
+	}
+	return writeBytes(w, data)
+}
+
+func writeBytes(w *bufio.Writer, b []byte) error {
+	var lenBuf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(lenBuf[:], uint64(len(b)))
+	if _, err := w.Write(lenBuf[:n]); err != nil {
+		return err
+	}
+	_, err := w.Write(b)
+	return err
+}
+
+// Restore replaces the contents of m with the tree encoded in r by a
+// previous call to Snapshot.
+func (m *MemMapFs) Restore(r io.Reader) error {
+	br := bufio.NewReader(r)
+
+	magic := make([]byte, len(snapshotMagic))
+	if _, err := io.ReadFull(br, magic); err != nil {
+		return err
+	}
+	if string(magic) != snapshotMagic {
+		return fmt.Errorf("mem: unrecognized snapshot format %q", magic)
+	}
+
+	m.mu.Lock()
+	m.data = map[string]*FileData{FilePathSeparator: CreateDir(FilePathSeparator)}
+	m.mu.Unlock()
+
+	for {
+		path, err := readBytes(br)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var modeBits uint32
+		if err := binary.Read(br, binary.LittleEndian, &modeBits); err != nil {
+			return err
+		}
+		var modNano int64
+		if err := binary.Read(br, binary.LittleEndian, &modNano); err != nil {
+			return err
+		}
+		target, err := readBytes(br)
+		if err != nil {
+			return err
+		}
+		data, err := readBytes(br)
+		if err != nil {
+			return err
+		}
+
+		if err := m.restoreEntry(string(path), os.FileMode(modeBits), time.Unix(0, modNano), string(target), data); err != nil {
+			return err
+		}
+	}
+}
+
+func (m *MemMapFs) restoreEntry(path string, mode os.FileMode, modTime time.Time, target string, data []byte) error {
+	switch {
+	case mode&os.ModeSymlink != 0:
+		if err := m.SymlinkIfPossible(target, path); err != nil {
+			return err
+		}
+	case mode.IsDir():
+		if path == "/" {
+			return nil
+		}
+		if err := m.MkdirAll(path, mode.Perm()); err != nil {
+			return err
+		}
+	default:
+		if err := afero.WriteFile(m, path, data, mode.Perm()); err != nil {
+			return err
+		}
+	}
+	return m.Chtimes(path, modTime, modTime)
+}
+
+func readBytes(r *bufio.Reader) ([]byte, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, n)
+	if n > 0 {
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+	}
+	return buf, nil
+}
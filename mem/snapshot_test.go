@@ -0,0 +1,106 @@
+package mem
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+func TestSnapshotRestoreRoundTrip(t *testing.T) {
+	src := NewMemMapFs().(*MemMapFs)
+
+	if err := src.MkdirAll("/a/b", 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := afero.WriteFile(src, "/a/b/hello.txt", []byte("hello, world\n"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+	modTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := src.Chtimes("/a/b/hello.txt", modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := src.Snapshot(&buf); err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+
+	dst := NewMemMapFs().(*MemMapFs)
+	if err := dst.Restore(&buf); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+
+	got, err := afero.ReadFile(dst, "/a/b/hello.txt")
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if string(got) != "hello, world\n" {
+		t.Errorf("content = %q, want %q", got, "hello, world\n")
+	}
+
+	fi, err := dst.Stat("/a/b/hello.txt")
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if fi.Mode().Perm() != 0o640 {
+		t.Errorf("mode = %v, want %v", fi.Mode().Perm(), os.FileMode(0o640))
+	}
+	if !fi.ModTime().Equal(modTime) {
+		t.Errorf("modtime = %v, want %v", fi.ModTime(), modTime)
+	}
+
+	dirInfo, err := dst.Stat("/a/b")
+	if err != nil {
+		t.Fatalf("Stat dir: %v", err)
+	}
+	if !dirInfo.IsDir() {
+		t.Error("expected /a/b to round-trip as a directory")
+	}
+}
+
+func TestSnapshotRestoreRoundTripsSymlink(t *testing.T) {
+	src := NewMemMapFs().(*MemMapFs)
+
+	if err := afero.WriteFile(src, "/real.txt", []byte("target"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := src.SymlinkIfPossible("/real.txt", "/link.txt"); err != nil {
+		t.Fatalf("SymlinkIfPossible: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := src.Snapshot(&buf); err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+
+	dst := NewMemMapFs().(*MemMapFs)
+	if err := dst.Restore(&buf); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+
+	fi, err := dst.Stat("/link.txt")
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if fi.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("expected /link.txt to round-trip as a symlink, got mode %v", fi.Mode())
+	}
+	target, err := dst.ReadlinkIfPossible("/link.txt")
+	if err != nil {
+		t.Fatalf("ReadlinkIfPossible: %v", err)
+	}
+	if target != "/real.txt" {
+		t.Errorf("link target = %q, want %q", target, "/real.txt")
+	}
+}
+
+func TestSnapshotRestoreRejectsUnknownFormat(t *testing.T) {
+	dst := NewMemMapFs().(*MemMapFs)
+	err := dst.Restore(bytes.NewReader([]byte("not a snapshot")))
+	if err == nil {
+		t.Fatal("expected an error restoring an unrecognized stream")
+	}
+}
@@ -0,0 +1,62 @@
+package mem
+
+import (
+	"errors"
+	"os"
+
+	"github.com/spf13/afero"
+)
+
+var (
+	_ afero.Linker     = (*MemMapFs)(nil)
+	_ afero.LinkReader = (*MemMapFs)(nil)
+	_ afero.Lstater    = (*MemMapFs)(nil)
+)
+
+// SymlinkIfPossible creates newname as a symlink to oldname, implementing
+// afero.Linker. oldname is stored verbatim as the link target; MemMapFs
+// never resolves it against newname's content, so Open/Read/Stat on a
+// symlink behave the same as on any other entry.
+func (m *MemMapFs) SymlinkIfPossible(oldname, newname string) error {
+	newname = normalizePath(newname)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.data[newname]; ok {
+		return &os.LinkError{Op: "symlink", Old: oldname, New: newname, Err: os.ErrExist}
+	}
+	d := CreateSymlink(newname, oldname)
+	m.data[newname] = d
+	if err := m.registerWithParent(d); err != nil {
+		delete(m.data, newname)
+		return err
+	}
+	return nil
+}
+
+// ReadlinkIfPossible returns the target of the symlink at name, implementing
+// afero.LinkReader.
+func (m *MemMapFs) ReadlinkIfPossible(name string) (string, error) {
+	name = normalizePath(name)
+	m.mu.RLock()
+	d, ok := m.data[name]
+	m.mu.RUnlock()
+	if !ok {
+		return "", &os.PathError{Op: "readlink", Path: name, Err: os.ErrNotExist}
+	}
+
+	d.Lock()
+	defer d.Unlock()
+	if d.mode&os.ModeSymlink == 0 {
+		return "", &os.PathError{Op: "readlink", Path: name, Err: errors.New("mem: not a symlink")}
+	}
+	return d.symlinkTarget, nil
+}
+
+// LstatIfPossible implements afero.Lstater. MemMapFs never follows a
+// symlink's target when accessing it through any other call, so Lstat and
+// Stat agree and the second return value is always true.
+func (m *MemMapFs) LstatIfPossible(name string) (os.FileInfo, bool, error) {
+	fi, err := m.Stat(name)
+	return fi, true, err
+}